@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/livekit/egress/pkg/errors"
+)
+
+// supportedStreamSchemes are the URL schemes accepted for stream egress output urls.
+var supportedStreamSchemes = map[string]bool{
+	"rtmp":  true,
+	"rtmps": true,
+	"srt":   true,
+}
+
+// ValidateStreamURL checks that a stream output url uses a supported scheme and,
+// for SRT, that its mode is one this pipeline can drive.
+func ValidateStreamURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.ErrInvalidUrl(rawURL, err.Error())
+	}
+
+	if !supportedStreamSchemes[u.Scheme] {
+		return errors.ErrInvalidUrl(rawURL, fmt.Sprintf("unsupported scheme %q", u.Scheme))
+	}
+
+	if u.Scheme == "srt" {
+		switch mode := u.Query().Get("mode"); mode {
+		case "", "caller", "listener":
+		default:
+			return errors.ErrInvalidUrl(rawURL, fmt.Sprintf("unsupported srt mode %q", mode))
+		}
+	}
+
+	return nil
+}
+
+// IsSRTUrl returns true if the given stream output url uses the srt scheme.
+func IsSRTUrl(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "srt"
+}
@@ -0,0 +1,9 @@
+package config
+
+// Rendition describes one rung of an adaptive-bitrate segmented output ladder.
+type Rendition struct {
+	Width     int
+	Height    int
+	Bitrate   int // kbps, passed to x264enc's bitrate property
+	FrameRate int
+}
@@ -0,0 +1,13 @@
+package config
+
+// SegmentContainer selects the container format used for segmented egress output.
+type SegmentContainer string
+
+const (
+	SegmentContainerMPEGTS SegmentContainer = "MPEG_TS"
+	SegmentContainerFMP4   SegmentContainer = "FMP4"
+)
+
+// DefaultSegmentContainer is used when SegmentParams.SegmentContainer is left unset,
+// preserving existing behavior for callers that predate this option.
+const DefaultSegmentContainer = SegmentContainerMPEGTS
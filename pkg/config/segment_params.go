@@ -0,0 +1,37 @@
+package config
+
+import (
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+)
+
+// SegmentParams holds the segmented-output-specific parameters for an OutputConfig.
+type SegmentParams struct {
+	SegmentSuffix livekit.SegmentedFileSuffix
+
+	// SegmentContainer selects MPEG-TS (default) vs fMP4/CMAF segmented output.
+	SegmentContainer SegmentContainer
+
+	// DashManifestFilename is the local/remote name of the DASH .mpd sidecar written
+	// alongside the HLS playlist when SegmentContainer is SegmentContainerFMP4.
+	DashManifestFilename string
+
+	// LivePlaylistUploadDebounce collapses bursts of segment rollovers (e.g. right after
+	// a discontinuity) into a single playlist upload. Defaults to 1s if unset.
+	LivePlaylistUploadDebounce time.Duration
+
+	// LivePlaylistUploadBackoff is the starting delay for retrying a failed live
+	// playlist upload; it doubles on each attempt up to a fixed number of retries.
+	// Defaults to 1s if unset.
+	LivePlaylistUploadBackoff time.Duration
+
+	// Renditions configures an adaptive-bitrate ladder: one SegmentOutput per rendition,
+	// each with its own encoder branch, plus a master playlist referencing all of them.
+	// Leave empty for a single-rendition segmented output.
+	Renditions []Rendition
+}
+
+// DefaultDashManifestFilename is used when SegmentContainer is SegmentContainerFMP4 and
+// DashManifestFilename is left unset, so the DASH sidecar always has somewhere to land.
+const DefaultDashManifestFilename = "manifest.mpd"
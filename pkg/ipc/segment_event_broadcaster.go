@@ -0,0 +1,64 @@
+package ipc
+
+import (
+	"context"
+	"sync"
+)
+
+// SegmentEventBroadcaster backs the egress client's SegmentEvents psrpc stream: the
+// controller calls PublishSegmentEvent for every SegmentEventPayload it receives from a pipeline,
+// and the psrpc stream handler for a given egress ID reads from the channel returned
+// by Subscribe and forwards each event to the connected client.
+type SegmentEventBroadcaster struct {
+	mu   sync.Mutex
+	subs map[string][]chan *SegmentEventPayload
+}
+
+func NewSegmentEventBroadcaster() *SegmentEventBroadcaster {
+	return &SegmentEventBroadcaster{
+		subs: make(map[string][]chan *SegmentEventPayload),
+	}
+}
+
+// Subscribe registers a new listener for the given egress ID's SegmentEvents stream.
+// The returned channel is closed by Unsubscribe.
+func (b *SegmentEventBroadcaster) Subscribe(egressID string) <-chan *SegmentEventPayload {
+	ch := make(chan *SegmentEventPayload, 16)
+
+	b.mu.Lock()
+	b.subs[egressID] = append(b.subs[egressID], ch)
+	b.mu.Unlock()
+
+	return ch
+}
+
+// Unsubscribe removes and closes a channel previously returned by Subscribe.
+func (b *SegmentEventBroadcaster) Unsubscribe(egressID string, ch <-chan *SegmentEventPayload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subs := b.subs[egressID]
+	for i, sub := range subs {
+		if sub == ch {
+			close(sub)
+			b.subs[egressID] = append(subs[:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// PublishSegmentEvent implements SegmentEventsClient, fanning the event out to every subscriber
+// of this egress ID's SegmentEvents stream. Slow subscribers drop events rather than
+// block the pipeline.
+func (b *SegmentEventBroadcaster) PublishSegmentEvent(_ context.Context, event *SegmentEventPayload) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subs[event.EgressID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package ipc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentEventBroadcasterPublishSubscribe(t *testing.T) {
+	b := NewSegmentEventBroadcaster()
+
+	ch := b.Subscribe("egress1")
+	require.NoError(t, b.PublishSegmentEvent(context.Background(), &SegmentEventPayload{EgressID: "egress1", FragmentId: 1}))
+
+	select {
+	case event := <-ch:
+		require.Equal(t, uint(1), event.FragmentId)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber never received published event")
+	}
+
+	b.Unsubscribe("egress1", ch)
+	require.NoError(t, b.PublishSegmentEvent(context.Background(), &SegmentEventPayload{EgressID: "egress1", FragmentId: 2}))
+
+	_, ok := <-ch
+	require.False(t, ok, "channel should be closed after Unsubscribe")
+}
+
+func TestSegmentEventBroadcasterIsolatesEgressIDs(t *testing.T) {
+	b := NewSegmentEventBroadcaster()
+
+	ch := b.Subscribe("egress1")
+	require.NoError(t, b.PublishSegmentEvent(context.Background(), &SegmentEventPayload{EgressID: "egress2", FragmentId: 1}))
+
+	select {
+	case <-ch:
+		t.Fatal("subscriber for egress1 should not receive egress2's events")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
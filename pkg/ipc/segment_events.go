@@ -0,0 +1,22 @@
+package ipc
+
+import "context"
+
+// SegmentEventPayload mirrors output.SegmentEvent without importing the pipeline
+// package, so it can be shared between the pipeline process and the controller.
+type SegmentEventPayload struct {
+	EgressID       string
+	FragmentId     uint
+	Filename       string
+	StartPTS       int64
+	StartWallClock int64
+	Duration       int64
+	ByteSize       int64
+}
+
+// SegmentEventsClient is implemented by the existing ipc service client used by the
+// controller. The pipeline calls PublishSegmentEvent once per closed segment; the
+// controller relays it over the egress client's SegmentEvents psrpc stream.
+type SegmentEventsClient interface {
+	PublishSegmentEvent(ctx context.Context, event *SegmentEventPayload) error
+}
@@ -0,0 +1,75 @@
+package builder
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/tinyzimmer/go-gst/gst"
+
+	"github.com/livekit/egress/pkg/config"
+	"github.com/livekit/egress/pkg/errors"
+)
+
+// buildStreamSink creates the sink element for a single stream output url, choosing
+// between rtmp2sink and srtsink based on the url scheme. It's called from
+// StreamBin.AddStream for every url added to a mixed RTMP+SRT stream set.
+func buildStreamSink(streamUrl string) (*gst.Element, error) {
+	if config.IsSRTUrl(streamUrl) {
+		return buildSRTSink(streamUrl)
+	}
+	return buildRTMPSink(streamUrl)
+}
+
+func buildRTMPSink(streamUrl string) (*gst.Element, error) {
+	sink, err := gst.NewElement("rtmp2sink")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = sink.SetProperty("location", streamUrl); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	return sink, nil
+}
+
+// buildSRTSink builds an srtsink for urls of the form
+// srt://host:port?mode=caller|listener&passphrase=...&latency=...
+func buildSRTSink(streamUrl string) (*gst.Element, error) {
+	u, err := url.Parse(streamUrl)
+	if err != nil {
+		return nil, errors.ErrInvalidUrl(streamUrl, err.Error())
+	}
+
+	sink, err := gst.NewElement("srtsink")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+
+	q := u.Query()
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = "caller"
+	}
+	if err = sink.SetProperty("uri", fmt.Sprintf("srt://%s%s", u.Host, u.Path)); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = sink.SetProperty("mode", mode); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if passphrase := q.Get("passphrase"); passphrase != "" {
+		if err = sink.SetProperty("passphrase", passphrase); err != nil {
+			return nil, errors.ErrGstPipelineError(err)
+		}
+	}
+	if latency := q.Get("latency"); latency != "" {
+		ms, err := strconv.Atoi(latency)
+		if err != nil {
+			return nil, errors.ErrInvalidUrl(streamUrl, fmt.Sprintf("invalid latency %q", latency))
+		}
+		if err = sink.SetProperty("latency", uint(ms)); err != nil {
+			return nil, errors.ErrGstPipelineError(err)
+		}
+	}
+
+	return sink, nil
+}
@@ -0,0 +1,81 @@
+package output
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bep/debounce"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// PlaylistUploadWarning is posted on the sink's bus when a live playlist upload
+// ultimately fails after exhausting retries. It's a warning, not a fatal error:
+// a stalled upload shouldn't tear down an otherwise-healthy egress.
+type PlaylistUploadWarning struct {
+	Error string
+}
+
+// livePlaylistUploader pushes the live manifest(s) after every segment rollover,
+// debouncing bursts of rollovers and retrying failures with capped exponential backoff.
+type livePlaylistUploader struct {
+	upload   func() error
+	onFail   func(err error)
+	debounce func(func())
+
+	backoffMin time.Duration
+	backoffMax time.Duration
+
+	mu      sync.Mutex
+	pending bool
+}
+
+func newLivePlaylistUploader(uploadDebounce, backoffMin, backoffMax time.Duration, upload func() error, onFail func(err error)) *livePlaylistUploader {
+	if uploadDebounce <= 0 {
+		uploadDebounce = time.Second
+	}
+	if backoffMin <= 0 {
+		backoffMin = time.Second
+	}
+	if backoffMax <= 0 {
+		backoffMax = 30 * time.Second
+	}
+
+	return &livePlaylistUploader{
+		upload:     upload,
+		onFail:     onFail,
+		debounce:   debounce.New(uploadDebounce),
+		backoffMin: backoffMin,
+		backoffMax: backoffMax,
+	}
+}
+
+// Trigger schedules a debounced upload attempt. Safe to call on every segment rollover.
+func (u *livePlaylistUploader) Trigger() {
+	u.debounce(u.uploadWithBackoff)
+}
+
+const maxPlaylistUploadAttempts = 5
+
+func (u *livePlaylistUploader) uploadWithBackoff() {
+	backoff := u.backoffMin
+
+	var err error
+	for attempt := 0; attempt < maxPlaylistUploadAttempts; attempt++ {
+		if err = u.upload(); err == nil {
+			return
+		}
+
+		logger.Warnw("failed to upload live playlist, retrying", err, "attempt", attempt, "backoff", backoff)
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > u.backoffMax {
+			backoff = u.backoffMax
+		}
+	}
+
+	logger.Warnw("giving up on live playlist upload", err, "attempts", maxPlaylistUploadAttempts)
+	if u.onFail != nil {
+		u.onFail(err)
+	}
+}
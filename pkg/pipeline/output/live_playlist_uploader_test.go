@@ -0,0 +1,55 @@
+package output
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLivePlaylistUploaderRetriesWithBackoff(t *testing.T) {
+	var attempts int32
+	var failed atomic.Bool
+
+	u := newLivePlaylistUploader(
+		time.Millisecond, time.Millisecond, 10*time.Millisecond,
+		func() error {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return errors.New("upload failed")
+			}
+			return nil
+		},
+		func(err error) { failed.Store(true) },
+	)
+
+	u.Trigger()
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&attempts) == 3
+	}, time.Second, time.Millisecond)
+	require.False(t, failed.Load())
+}
+
+func TestLivePlaylistUploaderGivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts int32
+	failed := make(chan error, 1)
+
+	u := newLivePlaylistUploader(
+		time.Millisecond, time.Millisecond, time.Millisecond,
+		func() error {
+			atomic.AddInt32(&attempts, 1)
+			return errors.New("upload failed")
+		},
+		func(err error) { failed <- err },
+	)
+
+	u.Trigger()
+	select {
+	case err := <-failed:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("onFail was never called")
+	}
+	require.EqualValues(t, maxPlaylistUploadAttempts, atomic.LoadInt32(&attempts))
+}
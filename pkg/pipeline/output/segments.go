@@ -1,14 +1,18 @@
 package output
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/tinyzimmer/go-gst/gst"
 
 	"github.com/livekit/egress/pkg/config"
 	"github.com/livekit/egress/pkg/errors"
+	"github.com/livekit/egress/pkg/ipc"
 	"github.com/livekit/egress/pkg/pipeline/builder"
 	"github.com/livekit/protocol/livekit"
 	"github.com/livekit/protocol/logger"
@@ -20,15 +24,118 @@ type SegmentOutput struct {
 	sink      *gst.Element
 	h264parse *gst.Element
 
+	container config.SegmentContainer
 	startDate time.Time
+
+	// fMP4/CMAF only: the init segment is written once, ahead of the first media segment
+	initWritten bool
+
+	playlistUploader *livePlaylistUploader
+	playlist         *segmentPlaylistWriter
+
+	// previous segment, reported as closed once the next one starts
+	prevFragmentId     uint
+	prevSegmentPath    string
+	prevSegmentStartAt time.Time
+	prevSegmentPTS     time.Duration
+
+	discontinuityMu      sync.Mutex
+	pendingDiscontinuity bool
+	periodIndex          int
+
+	egressID            string
+	segmentEventsClient ipc.SegmentEventsClient
+}
+
+// DiscontinuityEvent is posted on the sink's bus when a requested discontinuity takes
+// effect, starting a new playlist period at FragmentId. The segment playlist writer
+// does not act on it: the HLS playlist gets no #EXT-X-DISCONTINUITY tag and the DASH
+// manifest keeps a single Period; only the init segment named by #EXT-X-MAP and the
+// SegmentTemplate changes, for every listed segment.
+type DiscontinuityEvent struct {
+	FragmentId uint
+}
+
+var (
+	activeSegmentOutputsMu sync.Mutex
+	// keyed by egressID; an ABR ladder registers one entry per rendition, so a single
+	// egress can have several outputs that all need to split together
+	activeSegmentOutputs = map[string][]*SegmentOutput{}
+)
+
+// InsertDiscontinuity is the RPC-facing entrypoint, parallel to UpdateStream: it looks
+// up the running segmented output(s) for egressID and forces an immediate split on all
+// of them, marking the start of a new period. Useful for ad insertion boundaries or
+// resolution changes mid-egress. For an ABR ladder this reaches every rendition, so
+// their periods stay aligned.
+func InsertDiscontinuity(egressID string) error {
+	activeSegmentOutputsMu.Lock()
+	outputs := append([]*SegmentOutput(nil), activeSegmentOutputs[egressID]...)
+	activeSegmentOutputsMu.Unlock()
+	if len(outputs) == 0 {
+		return errors.ErrInvalidParameter(fmt.Sprintf("no active segmented output for egress %s", egressID))
+	}
+
+	for _, s := range outputs {
+		s.insertDiscontinuity()
+	}
+	return nil
+}
+
+func (s *SegmentOutput) insertDiscontinuity() {
+	s.discontinuityMu.Lock()
+	s.pendingDiscontinuity = true
+	s.discontinuityMu.Unlock()
+
+	s.sink.Emit("split-now")
+}
+
+func (s *SegmentOutput) consumePendingDiscontinuity() bool {
+	s.discontinuityMu.Lock()
+	defer s.discontinuityMu.Unlock()
+
+	pending := s.pendingDiscontinuity
+	s.pendingDiscontinuity = false
+	return pending
+}
+
+// SegmentEvent is posted on the sink's bus once a segment file is closed, i.e. on the
+// next rollover. The IPC layer relays it over the egress client's SegmentEvents stream
+// so callers can index segments or trigger per-segment processing without polling storage.
+type SegmentEvent struct {
+	FragmentId     uint
+	Filename       string
+	StartPTS       int64 // segment's presentation timestamp, in the track's clock
+	StartWallClock int64 // UnixNano real time the segment started
+	Duration       int64 // nanoseconds
+	ByteSize       int64
 }
 
 type FirstSampleMetadata struct {
 	StartDate int64 // Real time date of the first media sample
+
+	// IsInitSegment is set when this callback corresponds to the fMP4/CMAF init
+	// segment rather than a media segment, so downstream playlist/manifest
+	// writers don't mistake it for the first media fragment.
+	IsInitSegment bool
 }
 
+const initSegmentFilename = "init.mp4"
+
 func (b *Bin) buildSegmentOutput(p *config.PipelineConfig, out *config.OutputConfig) (*SegmentOutput, error) {
-	s := &SegmentOutput{}
+	container := out.SegmentParams.SegmentContainer
+	if container == "" {
+		container = config.DefaultSegmentContainer
+	}
+	if container == config.SegmentContainerFMP4 && out.SegmentParams.DashManifestFilename == "" {
+		out.SegmentParams.DashManifestFilename = config.DefaultDashManifestFilename
+	}
+
+	s := &SegmentOutput{
+		container:           container,
+		egressID:            p.Info.EgressId,
+		segmentEventsClient: p.IPCClient,
+	}
 
 	base, err := b.buildOutputBase(p, out.EgressType)
 	if err != nil {
@@ -50,8 +157,26 @@ func (b *Bin) buildSegmentOutput(p *config.PipelineConfig, out *config.OutputCon
 	if err = sink.SetProperty("send-keyframe-requests", true); err != nil {
 		return nil, errors.ErrGstPipelineError(err)
 	}
-	if err = sink.SetProperty("muxer-factory", "mpegtsmux"); err != nil {
-		return nil, errors.ErrGstPipelineError(err)
+
+	switch container {
+	case config.SegmentContainerFMP4:
+		if err = sink.SetProperty("muxer-factory", "cmafmux"); err != nil {
+			return nil, errors.ErrGstPipelineError(err)
+		}
+		muxerProps, err := gst.NewStructureFromString(fmt.Sprintf(
+			"properties,fragment-duration=%d", time.Duration(out.SegmentDuration)*time.Second,
+		))
+		if err != nil {
+			return nil, errors.ErrGstPipelineError(err)
+		}
+		if err = sink.SetProperty("muxer-properties", muxerProps); err != nil {
+			return nil, errors.ErrGstPipelineError(err)
+		}
+
+	default:
+		if err = sink.SetProperty("muxer-factory", "mpegtsmux"); err != nil {
+			return nil, errors.ErrGstPipelineError(err)
+		}
 	}
 
 	_, err = sink.Connect("format-location-full", func(self *gst.Element, fragmentId uint, firstSample *gst.Sample) string {
@@ -62,28 +187,62 @@ func (b *Bin) buildSegmentOutput(p *config.PipelineConfig, out *config.OutputCon
 			logger.Infow("nil sample passed into 'format-location-full' event handler, assuming 0 pts")
 		}
 
+		// a pending InsertDiscontinuity call starts a new period: for fMP4/CMAF this
+		// segment becomes the init segment of the new period
+		if s.consumePendingDiscontinuity() {
+			s.initWritten = false
+			s.periodIndex++
+
+			str := gst.MarshalStructure(DiscontinuityEvent{FragmentId: fragmentId})
+			sink.GetBus().Post(gst.NewElementMessage(sink, str))
+		}
+
+		// for fMP4/CMAF, the very first callback writes the shared init segment rather
+		// than a media segment; every subsequent call is a regular media fragment
+		isInitSegment := s.container == config.SegmentContainerFMP4 && !s.initWritten
+
+		// post a single FirstSampleMetadata for the whole session: posting it again with
+		// a different StartDate for the init-segment callback let consumers race on which
+		// value they saw, so it's computed once here regardless of which branch follows
 		if s.startDate.IsZero() {
-			now := time.Now()
+			s.startDate = time.Now().Add(-pts)
 
-			s.startDate = now.Add(-pts)
+			str := gst.MarshalStructure(FirstSampleMetadata{
+				StartDate:     s.startDate.UnixNano(),
+				IsInitSegment: isInitSegment,
+			})
+			sink.GetBus().Post(gst.NewElementMessage(sink, str))
+		}
 
-			mdata := FirstSampleMetadata{
-				StartDate: now.UnixNano(),
-			}
-			str := gst.MarshalStructure(mdata)
-			msg := gst.NewElementMessage(sink, str)
-			sink.GetBus().Post(msg)
+		// a rollover means the previous segment (and the playlist referencing it) is
+		// final; report it before branching on isInitSegment so the last media segment
+		// of a period that just ended on a discontinuity is never silently dropped
+		if s.playlistUploader != nil {
+			s.playlistUploader.Trigger()
 		}
+		s.emitPrevSegmentEvent(sink, pts)
+		s.writePrevPlaylistEntry(pts)
 
-		var segmentName string
-		switch out.SegmentParams.SegmentSuffix {
-		case livekit.SegmentedFileSuffix_TIMESTAMP:
-			ts := s.startDate.Add(pts)
-			segmentName = fmt.Sprintf("%s_%s%03d.ts", out.SegmentPrefix, ts.Format("20060102150405"), ts.UnixMilli()%1000)
-		default:
-			segmentName = fmt.Sprintf("%s_%05d.ts", out.SegmentPrefix, fragmentId)
+		if isInitSegment {
+			s.initWritten = true
+			initName := s.initSegmentName()
+			if s.playlist != nil {
+				s.playlist.setInitName(initName)
+			}
+			// the init segment isn't itself a media segment to report on the next rollover
+			s.prevSegmentPath = ""
+			return path.Join(out.LocalDir, initName)
 		}
-		return path.Join(out.LocalDir, segmentName)
+
+		segmentName := s.segmentName(out, fragmentId, pts)
+		segmentPath := path.Join(out.LocalDir, segmentName)
+
+		s.prevFragmentId = fragmentId
+		s.prevSegmentPath = segmentPath
+		s.prevSegmentStartAt = time.Now()
+		s.prevSegmentPTS = pts
+
+		return segmentPath
 	})
 	if err != nil {
 		return nil, errors.ErrGstPipelineError(err)
@@ -97,9 +256,143 @@ func (b *Bin) buildSegmentOutput(p *config.PipelineConfig, out *config.OutputCon
 	s.h264parse = h264parse
 	s.sink = sink
 
+	initName := ""
+	if container == config.SegmentContainerFMP4 {
+		initName = initSegmentFilename
+	}
+	s.playlist = newSegmentPlaylistWriter(out, initName)
+
+	s.playlistUploader = newLivePlaylistUploader(
+		out.SegmentParams.LivePlaylistUploadDebounce,
+		out.SegmentParams.LivePlaylistUploadBackoff,
+		out.SegmentParams.LivePlaylistUploadBackoff*maxPlaylistUploadAttempts,
+		func() error { return s.uploadManifest(out) },
+		func(err error) {
+			str := gst.MarshalStructure(PlaylistUploadWarning{Error: err.Error()})
+			sink.GetBus().Post(gst.NewElementMessage(sink, str))
+		},
+	)
+
+	activeSegmentOutputsMu.Lock()
+	activeSegmentOutputs[s.egressID] = append(activeSegmentOutputs[s.egressID], s)
+	activeSegmentOutputsMu.Unlock()
+
 	return s, nil
 }
 
+// Close unregisters this output so InsertDiscontinuity can no longer reach it. Called
+// once the egress for this output has stopped.
+func (s *SegmentOutput) Close() {
+	activeSegmentOutputsMu.Lock()
+	defer activeSegmentOutputsMu.Unlock()
+
+	outputs := activeSegmentOutputs[s.egressID]
+	for i, o := range outputs {
+		if o == s {
+			outputs = append(outputs[:i], outputs[i+1:]...)
+			break
+		}
+	}
+	if len(outputs) == 0 {
+		delete(activeSegmentOutputs, s.egressID)
+	} else {
+		activeSegmentOutputs[s.egressID] = outputs
+	}
+}
+
+// initSegmentName returns the local filename of the fMP4/CMAF init segment for the
+// current period. Each period after a discontinuity gets its own init segment so a
+// player still referencing an earlier period's init file isn't broken by a later one
+// overwriting it.
+func (s *SegmentOutput) initSegmentName() string {
+	if s.periodIndex == 0 {
+		return initSegmentFilename
+	}
+	return fmt.Sprintf("init_%d.mp4", s.periodIndex)
+}
+
+// writePrevPlaylistEntry adds the segment that just closed to the live playlist (and
+// DASH manifest, for fMP4) and rewrites them to disk. No-op for the first segment.
+func (s *SegmentOutput) writePrevPlaylistEntry(pts time.Duration) {
+	if s.playlist == nil || s.prevSegmentPath == "" {
+		return
+	}
+	duration := pts - s.prevSegmentPTS
+	if err := s.playlist.addSegment(path.Base(s.prevSegmentPath), duration); err != nil {
+		logger.Infow("failed to write segment playlist", "error", err, "path", s.prevSegmentPath)
+	}
+}
+
+// emitPrevSegmentEvent posts a SegmentEvent for the segment that just closed, i.e. the
+// one written prior to this format-location-full callback. No-op for the very first
+// segment, since there's nothing to report on yet.
+func (s *SegmentOutput) emitPrevSegmentEvent(sink *gst.Element, pts time.Duration) {
+	if s.prevSegmentPath == "" {
+		return
+	}
+
+	var byteSize int64
+	if fi, err := os.Stat(s.prevSegmentPath); err == nil {
+		byteSize = fi.Size()
+	} else {
+		logger.Infow("failed to stat closed segment for SegmentEvent", "error", err, "path", s.prevSegmentPath)
+	}
+
+	event := SegmentEvent{
+		FragmentId:     s.prevFragmentId,
+		Filename:       s.prevSegmentPath,
+		StartPTS:       s.prevSegmentPTS.Nanoseconds(),
+		StartWallClock: s.prevSegmentStartAt.UnixNano(),
+		Duration:       (pts - s.prevSegmentPTS).Nanoseconds(),
+		ByteSize:       byteSize,
+	}
+	str := gst.MarshalStructure(event)
+	sink.GetBus().Post(gst.NewElementMessage(sink, str))
+
+	if s.segmentEventsClient != nil {
+		if err := s.segmentEventsClient.PublishSegmentEvent(context.Background(), &ipc.SegmentEventPayload{
+			EgressID:       s.egressID,
+			FragmentId:     event.FragmentId,
+			Filename:       event.Filename,
+			StartPTS:       event.StartPTS,
+			StartWallClock: event.StartWallClock,
+			Duration:       event.Duration,
+			ByteSize:       event.ByteSize,
+		}); err != nil {
+			logger.Warnw("failed to publish segment event", err, "path", s.prevSegmentPath)
+		}
+	}
+}
+
+// uploadManifest pushes the current live playlist (and DASH manifest, if present)
+// to the configured output storage. Called after every segment rollover.
+func (s *SegmentOutput) uploadManifest(out *config.OutputConfig) error {
+	if err := s.outputBase.uploadLocalFile(out.PlaylistFilename); err != nil {
+		return err
+	}
+	if out.SegmentParams.SegmentContainer == config.SegmentContainerFMP4 {
+		if err := s.outputBase.uploadLocalFile(out.SegmentParams.DashManifestFilename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SegmentOutput) segmentName(out *config.OutputConfig, fragmentId uint, pts time.Duration) string {
+	ext := "ts"
+	if s.container == config.SegmentContainerFMP4 {
+		ext = "m4s"
+	}
+
+	switch out.SegmentParams.SegmentSuffix {
+	case livekit.SegmentedFileSuffix_TIMESTAMP:
+		ts := s.startDate.Add(pts)
+		return fmt.Sprintf("%s_%s%03d.%s", out.SegmentPrefix, ts.Format("20060102150405"), ts.UnixMilli()%1000, ext)
+	default:
+		return fmt.Sprintf("%s_%05d.%s", out.SegmentPrefix, fragmentId, ext)
+	}
+}
+
 func (o *SegmentOutput) Link() error {
 	// link audio to sink
 	if o.audioQueue != nil {
@@ -0,0 +1,239 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/tinyzimmer/go-gst/gst"
+
+	"github.com/livekit/egress/pkg/config"
+	"github.com/livekit/egress/pkg/errors"
+	"github.com/livekit/egress/pkg/pipeline/builder"
+)
+
+// ABRSegmentOutput produces an adaptive-bitrate HLS ladder: one SegmentOutput per
+// configured rendition, each with its own encoder branch and splitmuxsink, plus a
+// master playlist referencing every rendition's playlist.
+type ABRSegmentOutput struct {
+	*outputBase
+
+	// decoder chain bridging the pipeline's single pre-encoded video queue back to raw
+	// video, since the tee must hand every rendition's videoscale a raw frame to rescale
+	h264parse *gst.Element
+	decoder   *gst.Element
+	videorate *gst.Element
+	tee       *gst.Element
+
+	renditions []*SegmentOutput
+
+	out                *config.OutputConfig
+	masterPlaylistPath string
+}
+
+const masterPlaylistFilename = "master.m3u8"
+
+// buildABRSegmentOutput fans a decoded copy of the video out through one
+// videoscale+x264enc+splitmuxsink branch per rendition. All branches hang off a shared
+// tee so every rendition sees the same raw frames and forced-keyframe boundaries,
+// keeping their segments aligned for rendition switching.
+func (b *Bin) buildABRSegmentOutput(p *config.PipelineConfig, out *config.OutputConfig) (*ABRSegmentOutput, error) {
+	renditions := out.SegmentParams.Renditions
+	if len(renditions) == 0 {
+		return nil, errors.ErrInvalidParameter("SegmentParams.Renditions")
+	}
+
+	base, err := b.buildOutputBase(p, out.EgressType)
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+
+	// the pipeline's video queue carries already-encoded H.264; videoscale needs raw
+	// frames, so decode once here and share the result across every rendition's tee leg
+	h264parse, err := gst.NewElement("h264parse")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	decoder, err := gst.NewElement("avdec_h264")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	videorate, err := gst.NewElement("videorate")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	tee, err := gst.NewElement("tee")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = b.bin.AddMany(h264parse, decoder, videorate, tee); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = gst.ElementLinkMany(h264parse, decoder, videorate, tee); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+
+	a := &ABRSegmentOutput{
+		outputBase:         base,
+		h264parse:          h264parse,
+		decoder:            decoder,
+		videorate:          videorate,
+		tee:                tee,
+		out:                out,
+		masterPlaylistPath: path.Join(out.LocalDir, masterPlaylistFilename),
+	}
+
+	for _, r := range renditions {
+		rendition, err := b.buildRenditionOutput(p, out, tee, r)
+		if err != nil {
+			return nil, err
+		}
+		a.renditions = append(a.renditions, rendition)
+	}
+
+	return a, nil
+}
+
+// buildRenditionOutput builds one rung of the ABR ladder: a videoscale+x264enc branch
+// off the shared tee, feeding a SegmentOutput of its own under a per-rendition subdir.
+func (b *Bin) buildRenditionOutput(p *config.PipelineConfig, out *config.OutputConfig, tee *gst.Element, r config.Rendition) (*SegmentOutput, error) {
+	queue, err := gst.NewElement("queue")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	videoscale, err := gst.NewElement("videoscale")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	capsFilter, err := gst.NewElement("capsfilter")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	caps := gst.NewCapsFromString(fmt.Sprintf(
+		"video/x-raw,width=%d,height=%d,framerate=%d/1", r.Width, r.Height, r.FrameRate,
+	))
+	if err = capsFilter.SetProperty("caps", caps); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+
+	x264enc, err := gst.NewElement("x264enc")
+	if err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = x264enc.SetProperty("bitrate", uint(r.Bitrate)); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	// align GOPs to the segment boundary so every rendition splits at the same keyframe
+	if err = x264enc.SetProperty("key-int-max", uint(r.FrameRate*out.SegmentDuration)); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+
+	if err = b.bin.AddMany(queue, videoscale, capsFilter, x264enc); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = gst.ElementLinkMany(queue, videoscale, capsFilter, x264enc); err != nil {
+		return nil, errors.ErrGstPipelineError(err)
+	}
+	if err = builder.LinkPads(
+		"tee", tee.GetRequestPad("src_%u"),
+		"rendition queue", queue.GetStaticPad("sink"),
+	); err != nil {
+		return nil, err
+	}
+
+	renditionOut := *out
+	renditionOut.SegmentParams.Renditions = nil
+	renditionOut.SegmentPrefix = fmt.Sprintf("%s_%dp", out.SegmentPrefix, r.Height)
+	renditionOut.LocalDir = path.Join(out.LocalDir, fmt.Sprintf("%dp", r.Height))
+
+	rendition, err := b.buildSegmentOutput(p, &renditionOut)
+	if err != nil {
+		return nil, err
+	}
+	// this rendition is encoded from its own branch off the shared tee, not the
+	// pipeline's single pre-encoded video queue
+	rendition.videoQueue = x264enc
+
+	return rendition, nil
+}
+
+func (a *ABRSegmentOutput) Link() error {
+	if a.videoQueue != nil {
+		if err := builder.LinkPads(
+			"video queue", a.videoQueue.GetStaticPad("src"),
+			"h264parse", a.h264parse.GetStaticPad("sink"),
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, rendition := range a.renditions {
+		// audio isn't re-encoded per rendition; every rung shares the ladder's audio queue
+		rendition.audioQueue = a.audioQueue
+		if err := rendition.Link(); err != nil {
+			return err
+		}
+	}
+
+	if err := a.writeMasterPlaylist(a.out); err != nil {
+		return errors.ErrGstPipelineError(err)
+	}
+	if err := a.outputBase.uploadLocalFile(masterPlaylistFilename); err != nil {
+		return errors.ErrGstPipelineError(err)
+	}
+
+	return nil
+}
+
+// writeMasterPlaylist writes the top-level .m3u8 referencing every rendition's own
+// playlist, ordered highest bitrate first, so players default to the best rendition
+// their bandwidth estimate allows.
+func (a *ABRSegmentOutput) writeMasterPlaylist(out *config.OutputConfig) error {
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+
+	renditions := append([]config.Rendition(nil), out.SegmentParams.Renditions...)
+	sort.SliceStable(renditions, func(i, j int) bool {
+		return renditions[i].Bitrate > renditions[j].Bitrate
+	})
+
+	for _, r := range renditions {
+		sb.WriteString(fmt.Sprintf(
+			"#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s\n",
+			r.Bitrate*1000, r.Width, r.Height, path.Join(fmt.Sprintf("%dp", r.Height), out.PlaylistFilename),
+		))
+	}
+
+	return os.WriteFile(a.masterPlaylistPath, []byte(sb.String()), 0644)
+}
+
+// SegmentedOutput is implemented by both the single-rendition SegmentOutput and the
+// multi-rendition ABRSegmentOutput, so the pipeline builder can treat a segmented output
+// uniformly regardless of whether an ABR ladder was requested.
+type SegmentedOutput interface {
+	Link() error
+
+	// Close unregisters the output(s) from InsertDiscontinuity's registry. The pipeline
+	// calls this once the egress using this output has stopped.
+	Close()
+}
+
+// Close unregisters every rendition so a late InsertDiscontinuity call can no longer
+// reach a sink that's already been torn down.
+func (a *ABRSegmentOutput) Close() {
+	for _, rendition := range a.renditions {
+		rendition.Close()
+	}
+}
+
+// BuildSegmentedOutput is the entry point the pipeline builder calls for a segmented
+// egress output. It builds an ABR ladder when the output config has Renditions
+// configured, or a single-rendition SegmentOutput otherwise.
+func (b *Bin) BuildSegmentedOutput(p *config.PipelineConfig, out *config.OutputConfig) (SegmentedOutput, error) {
+	if len(out.SegmentParams.Renditions) > 0 {
+		return b.buildABRSegmentOutput(p, out)
+	}
+	return b.buildSegmentOutput(p, out)
+}
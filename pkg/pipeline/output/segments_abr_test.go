@@ -0,0 +1,34 @@
+package output
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/egress/pkg/config"
+)
+
+func TestWriteMasterPlaylist(t *testing.T) {
+	dir := t.TempDir()
+	out := &config.OutputConfig{
+		PlaylistFilename: "live.m3u8",
+		SegmentParams: config.SegmentParams{
+			Renditions: []config.Rendition{
+				{Width: 640, Height: 360, Bitrate: 800, FrameRate: 30},
+				{Width: 1280, Height: 720, Bitrate: 2500, FrameRate: 30},
+			},
+		},
+	}
+	a := &ABRSegmentOutput{masterPlaylistPath: path.Join(dir, masterPlaylistFilename)}
+
+	require.NoError(t, a.writeMasterPlaylist(out))
+
+	content, err := os.ReadFile(a.masterPlaylistPath)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "BANDWIDTH=800000,RESOLUTION=640x360")
+	require.Contains(t, string(content), "BANDWIDTH=2500000,RESOLUTION=1280x720")
+	require.Contains(t, string(content), path.Join("360p", "live.m3u8"))
+	require.Contains(t, string(content), path.Join("720p", "live.m3u8"))
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumePendingDiscontinuity(t *testing.T) {
+	s := &SegmentOutput{}
+	require.False(t, s.consumePendingDiscontinuity())
+
+	s.pendingDiscontinuity = true
+	require.True(t, s.consumePendingDiscontinuity())
+	require.False(t, s.consumePendingDiscontinuity(), "pending flag should be consumed exactly once")
+}
+
+func TestInitSegmentNamePerPeriod(t *testing.T) {
+	s := &SegmentOutput{}
+	require.Equal(t, initSegmentFilename, s.initSegmentName())
+
+	s.periodIndex = 1
+	require.Equal(t, "init_1.mp4", s.initSegmentName())
+
+	s.periodIndex = 2
+	require.Equal(t, "init_2.mp4", s.initSegmentName())
+}
+
+func TestInsertDiscontinuityRequiresActiveOutput(t *testing.T) {
+	require.Error(t, InsertDiscontinuity("no-such-egress"))
+}
+
+func TestRegistryFansOutOverRenditionsSharingAnEgressID(t *testing.T) {
+	// an ABR ladder registers one SegmentOutput per rendition under the same egress ID;
+	// none of them should clobber another's registration
+	rendition1 := &SegmentOutput{egressID: "abr-egress"}
+	rendition2 := &SegmentOutput{egressID: "abr-egress"}
+
+	activeSegmentOutputsMu.Lock()
+	activeSegmentOutputs["abr-egress"] = append(activeSegmentOutputs["abr-egress"], rendition1, rendition2)
+	activeSegmentOutputsMu.Unlock()
+	defer rendition1.Close()
+	defer rendition2.Close()
+
+	activeSegmentOutputsMu.Lock()
+	outputs := append([]*SegmentOutput(nil), activeSegmentOutputs["abr-egress"]...)
+	activeSegmentOutputsMu.Unlock()
+	require.ElementsMatch(t, []*SegmentOutput{rendition1, rendition2}, outputs)
+
+	rendition1.Close()
+	activeSegmentOutputsMu.Lock()
+	outputs = append([]*SegmentOutput(nil), activeSegmentOutputs["abr-egress"]...)
+	activeSegmentOutputsMu.Unlock()
+	require.ElementsMatch(t, []*SegmentOutput{rendition2}, outputs, "closing one rendition must not unregister the others")
+}
+
+func TestCloseUnregistersOutput(t *testing.T) {
+	s := &SegmentOutput{egressID: "test-egress"}
+	activeSegmentOutputsMu.Lock()
+	activeSegmentOutputs[s.egressID] = append(activeSegmentOutputs[s.egressID], s)
+	activeSegmentOutputsMu.Unlock()
+
+	s.Close()
+
+	require.Error(t, InsertDiscontinuity("test-egress"), "a closed output must not still be reachable")
+}
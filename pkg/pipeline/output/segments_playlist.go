@@ -0,0 +1,91 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livekit/egress/pkg/config"
+)
+
+type segmentPlaylistEntry struct {
+	filename string
+	duration time.Duration
+}
+
+// segmentPlaylistWriter maintains the live HLS playlist (and, for fMP4/CMAF, its DASH
+// sidecar) for a segmented output, rewriting both to disk as each segment closes.
+type segmentPlaylistWriter struct {
+	out      *config.OutputConfig
+	initName string // non-empty for fMP4/CMAF, referenced via #EXT-X-MAP
+
+	mu      sync.Mutex
+	entries []segmentPlaylistEntry
+}
+
+func newSegmentPlaylistWriter(out *config.OutputConfig, initName string) *segmentPlaylistWriter {
+	return &segmentPlaylistWriter{out: out, initName: initName}
+}
+
+// setInitName switches the init segment referenced by #EXT-X-MAP / SegmentTemplate,
+// e.g. after a discontinuity starts a new period with its own init segment.
+func (w *segmentPlaylistWriter) setInitName(initName string) {
+	w.mu.Lock()
+	w.initName = initName
+	w.mu.Unlock()
+}
+
+// addSegment appends a closed segment to the playlist and rewrites it (and the DASH
+// manifest, if this is an fMP4/CMAF output) to the output's local directory.
+func (w *segmentPlaylistWriter) addSegment(filename string, duration time.Duration) error {
+	w.mu.Lock()
+	w.entries = append(w.entries, segmentPlaylistEntry{filename: filename, duration: duration})
+	entries := append([]segmentPlaylistEntry(nil), w.entries...)
+	w.mu.Unlock()
+
+	if err := w.writeHLS(entries); err != nil {
+		return err
+	}
+	if w.initName == "" {
+		return nil
+	}
+	return w.writeDASH(entries)
+}
+
+func (w *segmentPlaylistWriter) writeHLS(entries []segmentPlaylistEntry) error {
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n#EXT-X-VERSION:7\n")
+	sb.WriteString(fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", w.out.SegmentDuration))
+	if w.initName != "" {
+		sb.WriteString(fmt.Sprintf("#EXT-X-MAP:URI=%q\n", w.initName))
+	}
+	for _, e := range entries {
+		sb.WriteString(fmt.Sprintf("#EXTINF:%.3f,\n%s\n", e.duration.Seconds(), e.filename))
+	}
+
+	return os.WriteFile(path.Join(w.out.LocalDir, w.out.PlaylistFilename), []byte(sb.String()), 0644)
+}
+
+// writeDASH emits a minimal live DASH .mpd sidecar so CMAF-aware players (dash.js,
+// Eyevinn's dash-mpd, etc.) can consume the fMP4 output without going through HLS.
+func (w *segmentPlaylistWriter) writeDASH(entries []segmentPlaylistEntry) error {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" profiles="urn:mpeg:dash:profile:isoff-live:2011">` + "\n")
+	sb.WriteString("  <Period>\n")
+	sb.WriteString("    <AdaptationSet mimeType=\"video/mp4\" segmentAlignment=\"true\">\n")
+	sb.WriteString("      <Representation id=\"0\">\n")
+	sb.WriteString(fmt.Sprintf(
+		"        <SegmentTemplate initialization=%q media=%q startNumber=\"1\" duration=\"%d\" />\n",
+		w.initName, w.out.SegmentPrefix+"_$Number%05d$.m4s", w.out.SegmentDuration,
+	))
+	sb.WriteString("      </Representation>\n")
+	sb.WriteString("    </AdaptationSet>\n")
+	sb.WriteString("  </Period>\n")
+	sb.WriteString("</MPD>\n")
+
+	return os.WriteFile(path.Join(w.out.LocalDir, w.out.SegmentParams.DashManifestFilename), []byte(sb.String()), 0644)
+}
@@ -0,0 +1,37 @@
+package output
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/livekit/egress/pkg/config"
+)
+
+func TestSegmentPlaylistWriterDASH(t *testing.T) {
+	dir := t.TempDir()
+	out := &config.OutputConfig{
+		SegmentDuration:  4,
+		SegmentPrefix:    "segment",
+		LocalDir:         dir,
+		PlaylistFilename: "live.m3u8",
+		SegmentParams: config.SegmentParams{
+			DashManifestFilename: config.DefaultDashManifestFilename,
+		},
+	}
+
+	w := newSegmentPlaylistWriter(out, "init.mp4")
+	require.NoError(t, w.addSegment("segment_00000.m4s", 4*time.Second))
+
+	hls, err := os.ReadFile(path.Join(dir, out.PlaylistFilename))
+	require.NoError(t, err)
+	require.Contains(t, string(hls), `#EXT-X-MAP:URI="init.mp4"`)
+	require.Contains(t, string(hls), "segment_00000.m4s")
+
+	mpd, err := os.ReadFile(path.Join(dir, config.DefaultDashManifestFilename))
+	require.NoError(t, err)
+	require.Contains(t, string(mpd), `initialization="init.mp4"`)
+}
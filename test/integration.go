@@ -32,6 +32,8 @@ const (
 	badStreamUrl   = "rtmp://sfo.contribute.live-video.net/app/fake1"
 	redactedBadUrl = "rtmp://sfo.contribute.live-video.net/app/*****"
 	webUrl         = "https://www.youtube.com/watch?v=wjQq0nSGS28&t=5205s"
+	srtStreamUrl   = "srt://localhost:8890?mode=caller"
+	redactedSrtUrl = srtStreamUrl
 )
 
 type testCase struct {
@@ -225,10 +227,10 @@ func runMultipleStreamTest(t *testing.T, conf *TestConfig, req *rpc.StartEgressR
 	require.Equal(t, test.expectVideoTranscoding, p.VideoTranscoding)
 	verifyStreams(t, p, conf, streamUrl1)
 
-	// add one good stream url and a couple bad ones
+	// add one good RTMP url, one good SRT url, and a bad one
 	_, err = conf.client.UpdateStream(ctx, egressID, &livekit.UpdateStreamRequest{
 		EgressId:      egressID,
-		AddOutputUrls: []string{badStreamUrl, streamUrl2},
+		AddOutputUrls: []string{badStreamUrl, streamUrl2, srtStreamUrl},
 	})
 	require.NoError(t, err)
 
@@ -236,10 +238,10 @@ func runMultipleStreamTest(t *testing.T, conf *TestConfig, req *rpc.StartEgressR
 
 	update := getUpdate(t, conf, egressID)
 	require.Equal(t, livekit.EgressStatus_EGRESS_ACTIVE.String(), update.Status.String())
-	require.Len(t, update.StreamResults, 3)
+	require.Len(t, update.StreamResults, 4)
 	for _, info := range update.StreamResults {
 		switch info.Url {
-		case redactedUrl1, redactedUrl2:
+		case redactedUrl1, redactedUrl2, redactedSrtUrl:
 			require.Equal(t, livekit.StreamInfo_ACTIVE.String(), info.Status.String())
 
 		case redactedBadUrl:
@@ -252,8 +254,8 @@ func runMultipleStreamTest(t *testing.T, conf *TestConfig, req *rpc.StartEgressR
 
 	require.Equal(t, test.expectVideoTranscoding, p.VideoTranscoding)
 
-	// verify the good stream urls
-	verifyStreams(t, p, conf, streamUrl1, streamUrl2)
+	// verify the good stream urls, including the SRT output
+	verifyStreams(t, p, conf, streamUrl1, streamUrl2, srtStreamUrl)
 
 	// remove one of the stream urls
 	_, err = conf.client.UpdateStream(ctx, egressID, &livekit.UpdateStreamRequest{
@@ -264,8 +266,8 @@ func runMultipleStreamTest(t *testing.T, conf *TestConfig, req *rpc.StartEgressR
 
 	time.Sleep(time.Second * 5)
 
-	// verify the remaining stream
-	verifyStreams(t, p, conf, streamUrl2)
+	// verify the remaining streams
+	verifyStreams(t, p, conf, streamUrl2, srtStreamUrl)
 
 	time.Sleep(time.Second * 10)
 
@@ -278,7 +280,7 @@ func runMultipleStreamTest(t *testing.T, conf *TestConfig, req *rpc.StartEgressR
 	require.NotZero(t, res.EndedAt)
 
 	// check stream info
-	require.Len(t, res.StreamResults, 3)
+	require.Len(t, res.StreamResults, 4)
 	for _, info := range res.StreamResults {
 		require.NotZero(t, info.StartedAt)
 		require.NotZero(t, info.EndedAt)
@@ -288,7 +290,7 @@ func runMultipleStreamTest(t *testing.T, conf *TestConfig, req *rpc.StartEgressR
 			require.Equal(t, livekit.StreamInfo_FINISHED.String(), info.Status.String())
 			require.Greater(t, float64(info.Duration)/1e9, 15.0)
 
-		case redactedUrl2:
+		case redactedUrl2, redactedSrtUrl:
 			require.Equal(t, livekit.StreamInfo_FINISHED.String(), info.Status.String())
 			require.Greater(t, float64(info.Duration)/1e9, 10.0)
 